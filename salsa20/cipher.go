@@ -0,0 +1,152 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salsa20
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+
+	"crypto/salsa20/salsa"
+)
+
+// blockSize is the size, in bytes, of a Salsa20 keystream block.
+const blockSize = 64
+
+// Cipher is a Salsa20 or XSalsa20 stream cipher instance. Unlike the
+// top-level XORKeyStream function, which re-derives the keystream from the
+// start of the keystream on every call, a Cipher keeps the keystream state
+// (including any unused tail of the last 64-byte block) between calls, so
+// callers may split their input across an arbitrary number of XORKeyStream
+// calls and get the same result as a single call over the concatenated
+// input. This makes Cipher suitable for use with cipher.StreamReader and
+// cipher.StreamWriter, and, together with SetCounter, for random-access
+// encryption or decryption.
+//
+// A *Cipher implements the cipher.Stream interface.
+type Cipher struct {
+	key    [32]byte
+	sigma  [16]byte
+	nonce  [8]byte
+	rounds int
+
+	counter uint64
+	buf     [blockSize]byte
+	// len is the number of unused keystream bytes buffered at the end of
+	// buf, left over from the last call to XORKeyStream.
+	len int
+}
+
+var _ cipher.Stream = (*Cipher)(nil)
+
+// NewUnauthenticatedCipher creates a new Salsa20 or XSalsa20 stream cipher
+// with the given key, nonce, and number of rounds.
+//
+// Key must be 16 or 32 bytes long, and nonce must be 8 or 24 bytes long. A
+// 24-byte nonce selects XSalsa20, deriving the effective key from the first
+// 16 bytes of the nonce via HSalsa20 as described in
+// https://cr.yp.to/snuffle/xsalsa-20081128.pdf. Rounds must be 8, 12, or 20;
+// passing 0 selects the default of 20. Invalid sizes return ErrKeySize,
+// ErrNonceSize, or ErrRounds.
+//
+// As the name indicates, this cipher does not provide any authentication,
+// and is vulnerable to active attacks that flip bits in the decrypted
+// plaintext unless the ciphertext is authenticated separately, for example
+// by using the salsa20poly1305 package.
+func NewUnauthenticatedCipher(key, nonce []byte, rounds int) (*Cipher, error) {
+	keyArr, sigma, err := preKeyErr(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if rounds == 0 {
+		rounds = 20
+	} else if rounds != 8 && rounds != 12 && rounds != 20 {
+		return nil, ErrRounds
+	}
+
+	c := &Cipher{key: *keyArr, sigma: *sigma, rounds: rounds}
+
+	switch len(nonce) {
+	case 8:
+		copy(c.nonce[:], nonce)
+	case 24:
+		var subKey [32]byte
+		var hNonce [16]byte
+		copy(hNonce[:], nonce[:16])
+		salsa.HSalsa20(&subKey, &hNonce, &c.key, sigma, rounds)
+		c.key = subKey
+		copy(c.nonce[:], nonce[16:24])
+	default:
+		return nil, ErrNonceSize
+	}
+
+	return c, nil
+}
+
+// SetCounter sets the Cipher's block counter, causing the next call to
+// XORKeyStream to seek to the keystream block at that offset, counted in
+// 64-byte blocks from the start of the keystream. Any keystream bytes
+// buffered by a previous non-block-aligned call to XORKeyStream are
+// discarded.
+func (c *Cipher) SetCounter(counter uint64) {
+	c.counter = counter
+	c.len = 0
+}
+
+// XORKeyStream XORs each byte in the given slice with a byte from the
+// cipher's keystream, implementing cipher.Stream. Dst and src must overlap
+// entirely or not at all.
+//
+// If len(dst) < len(src), XORKeyStream panics. It is acceptable to pass a
+// dst longer than src, in which case only the first len(src) bytes of dst
+// are modified.
+func (c *Cipher) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("salsa20: output smaller than input")
+	}
+	if len(src) == 0 {
+		return
+	}
+
+	if c.len != 0 {
+		keyStream := c.buf[blockSize-c.len:]
+		if len(src) < len(keyStream) {
+			keyStream = keyStream[:len(src)]
+		}
+		for i, b := range keyStream {
+			dst[i] = src[i] ^ b
+		}
+		c.len -= len(keyStream)
+		dst, src = dst[len(keyStream):], src[len(keyStream):]
+	}
+	if len(src) == 0 {
+		return
+	}
+
+	var subNonce [16]byte
+	copy(subNonce[:8], c.nonce[:])
+
+	if full := len(src) - len(src)%blockSize; full > 0 {
+		binary.LittleEndian.PutUint64(subNonce[8:], c.counter)
+		salsa.XORKeyStream(dst[:full], src[:full], &subNonce, &c.key, &c.sigma, c.rounds)
+		c.counter += uint64(full / blockSize)
+		dst, src = dst[full:], src[full:]
+	}
+
+	if len(src) > 0 {
+		for i := range c.buf {
+			c.buf[i] = 0
+		}
+		binary.LittleEndian.PutUint64(subNonce[8:], c.counter)
+		salsa.XORKeyStream(c.buf[:], c.buf[:], &subNonce, &c.key, &c.sigma, c.rounds)
+		c.counter++
+
+		n := copy(dst, src)
+		for i := 0; i < n; i++ {
+			dst[i] = src[i] ^ c.buf[i]
+		}
+		c.len = blockSize - n
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salsa20
+
+import "crypto/salsa20/salsa"
+
+// HSalsa20 applies the HSalsa20 core function to a 16-byte input in and a
+// 32-byte key k, and writes the 32-byte result to out. Rounds must be 8, 12,
+// or 20; passing 0 selects the default of 20.
+//
+// HSalsa20 is used internally to derive the subkey for XSalsa20 from its
+// 24-byte nonce, but it is also a standalone 256-bit-to-256-bit function
+// suitable for use as a keyed PRF or as a building block for a key
+// derivation function, matching NaCl's crypto_core_hsalsa20. See
+// https://cr.yp.to/snuffle/xsalsa-20081128.pdf for the construction.
+func HSalsa20(out *[32]byte, in *[16]byte, k *[32]byte, rounds int) {
+	if rounds == 0 {
+		rounds = 20
+	} else if rounds != 8 && rounds != 12 && rounds != 20 {
+		panic("salsa20: rounds must be 8, 12, or 20")
+	}
+	salsa.HSalsa20(out, in, k, &salsa.Sigma32, rounds)
+}
@@ -18,15 +18,30 @@ cipher.
 This package also implements XSalsa20: a version of Salsa20 with a 24-byte
 nonce as specified in https://cr.yp.to/snuffle/xsalsa-20081128.pdf. Simply
 passing a 24-byte slice as the nonce triggers XSalsa20.
+
+For callers that cannot segment their input the same way on both sides, or
+that need random access into the keystream, the Cipher type implements
+cipher.Stream and preserves keystream state between calls.
 */
 package salsa20 // import_ "golang.org/x/crypto/salsa20"
 
 // TODO(agl): implement XORKeyStream12 and XORKeyStream8 - the reduced round variants of Salsa20.
 
 import (
+	"errors"
+
 	"crypto/salsa20/salsa"
 )
 
+var (
+	// ErrKeySize is returned when a key is not 16 or 32 bytes long.
+	ErrKeySize = errors.New("salsa20: key must be 32 or 16 bytes")
+	// ErrNonceSize is returned when a nonce is not 8 or 24 bytes long.
+	ErrNonceSize = errors.New("salsa20: nonce must be 8 or 24 bytes")
+	// ErrRounds is returned when a round count is not 8, 12, or 20.
+	ErrRounds = errors.New("salsa20: rounds must be 8, 12, or 20")
+)
+
 // XORKeyStream crypts bytes from in to out using the given key and nonce. In
 // and out may be the same slice but otherwise should not overlap. Nonce must
 // be either 8 or 24 bytes long.
@@ -34,53 +49,87 @@ func XORKeyStream(out, in []byte, nonce []byte, key *[32]byte) {
 	xorKeyStream(out, in, nonce, key, &salsa.Sigma32, 20)
 }
 
-func XORKeyStreamWithRounds(out, in []byte, nonce []byte, key *[]byte, rounds int) {
+// XORKeyStreamWithRounds crypts bytes from in to out using the given key,
+// nonce and number of rounds. In and out may be the same slice but
+// otherwise should not overlap. Key must be 32 or 16 bytes long, nonce must
+// be 8 or 24 bytes long, and rounds must be 8, 12 or 20.
+func XORKeyStreamWithRounds(out, in []byte, nonce []byte, key []byte, rounds int) {
 	keyArr, sigma := preKey(key)
 	xorKeyStream(out, in, nonce, keyArr, sigma, rounds)
+}
 
+// XORKeyStreamErr is the non-panicking counterpart to XORKeyStream and
+// XORKeyStreamWithRounds. It crypts bytes from in to out using the given
+// key, nonce, and number of rounds (0 selects the default of 20), returning
+// ErrKeySize, ErrNonceSize, or ErrRounds instead of panicking if key, nonce,
+// or rounds are invalid. This makes it suitable for callers that derive
+// these sizes from untrusted input, such as network code.
+func XORKeyStreamErr(out, in, nonce, key []byte, rounds int) error {
+	keyArr, sigma, err := preKeyErr(key)
+	if err != nil {
+		return err
+	}
+	return xorKeyStreamErr(out, in, nonce, keyArr, sigma, rounds)
 }
 
 func xorKeyStream(out, in []byte, nonce []byte, key *[32]byte, sigma *[16]byte, rounds int) {
-	if rounds <= 0 {
+	if err := xorKeyStreamErr(out, in, nonce, key, sigma, rounds); err != nil {
+		panic(err)
+	}
+}
+
+func xorKeyStreamErr(out, in []byte, nonce []byte, key *[32]byte, sigma *[16]byte, rounds int) error {
+	if rounds == 0 {
 		rounds = 20
 	} else if rounds != 8 && rounds != 12 && rounds != 20 {
-		panic("salsa20: rounds must be 8, 12, 20")
+		return ErrRounds
 	}
 	if len(out) < len(in) {
 		in = in[:len(out)]
 	}
 	var subNonce [16]byte
 
-	if len(nonce) == 24 {
+	switch len(nonce) {
+	case 24:
 		var subKey [32]byte
 		var hNonce [16]byte
 		copy(hNonce[:], nonce[:16])
 		salsa.HSalsa20(&subKey, &hNonce, key, sigma, rounds)
 		copy(subNonce[:], nonce[16:])
 		key = &subKey
-	} else if len(nonce) == 8 {
-		copy(subNonce[:], nonce[:])
-	} else {
-		panic("salsa20: nonce must be 8 or 24 bytes")
+	case 8:
+		copy(subNonce[:], nonce)
+	default:
+		return ErrNonceSize
 	}
 
 	salsa.XORKeyStream(out, in, &subNonce, key, sigma, rounds)
+	return nil
+}
+
+func preKey(key []byte) (*[32]byte, *[16]byte) {
+	array, sigma, err := preKeyErr(key)
+	if err != nil {
+		panic(err)
+	}
+	return array, sigma
 }
 
-func preKey(keySlice *[]byte) (*[32]byte, *[16]byte) {
+func preKeyErr(key []byte) (*[32]byte, *[16]byte, error) {
 	var array [32]byte
 	var sigma [16]byte
 
-	if len(*keySlice) == 32 {
+	switch len(key) {
+	case 32:
 		sigma = salsa.Sigma32
-		copy(array[:], *keySlice)
-	} else if len(*keySlice) == 16 {
+		copy(array[:], key)
+	case 16:
 		sigma = salsa.Sigma16
-		copy(array[0:16], *keySlice)
-		copy(array[16:32], *keySlice)
-	} else {
-		panic("salsa20: key must be 32 or 16 bytes.")
+		copy(array[0:16], key)
+		copy(array[16:32], key)
+	default:
+		return nil, nil, ErrKeySize
 	}
 
-	return &array, &sigma
+	return &array, &sigma, nil
 }
@@ -0,0 +1,129 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package salsa20poly1305 implements the XSalsa20-Poly1305 authenticated
+encryption construction used by NaCl's secretbox API: XSalsa20 (a 24-byte
+nonce variant of Salsa20, see the salsa20 package) for confidentiality,
+combined with Poly1305 for authenticity. Seal derives a one-time Poly1305
+key from the first 32 bytes of the XSalsa20 keystream and authenticates the
+ciphertext produced from the rest of it; Open verifies the tag in constant
+time before decrypting.
+*/
+package salsa20poly1305 // import "crypto/salsa20poly1305"
+
+import (
+	"crypto/cipher"
+	"crypto/poly1305"
+	"crypto/subtle"
+	"errors"
+
+	"crypto/salsa20"
+)
+
+const (
+	// KeySize is the size, in bytes, of the key accepted by New.
+	KeySize = 32
+	// NonceSize is the size, in bytes, of the nonce accepted by Seal and Open.
+	NonceSize = 24
+	// Overhead is the size, in bytes, of the Poly1305 authentication tag
+	// appended to the ciphertext by Seal.
+	Overhead = poly1305.TagSize
+)
+
+// errOpen is returned by Open when authentication fails. It deliberately
+// carries no information about why, to avoid oracle attacks.
+var errOpen = errors.New("salsa20poly1305: message authentication failed")
+
+type xsalsa20poly1305 struct {
+	key [KeySize]byte
+}
+
+// New returns a cipher.AEAD implementing XSalsa20-Poly1305, as used by
+// NaCl's secretbox, for the given 32-byte key.
+func New(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, errors.New("salsa20poly1305: bad key length")
+	}
+	c := new(xsalsa20poly1305)
+	copy(c.key[:], key)
+	return c, nil
+}
+
+func (*xsalsa20poly1305) NonceSize() int { return NonceSize }
+func (*xsalsa20poly1305) Overhead() int  { return Overhead }
+
+func (c *xsalsa20poly1305) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != NonceSize {
+		panic("salsa20poly1305: bad nonce length passed to Seal")
+	}
+	if len(additionalData) != 0 {
+		panic("salsa20poly1305: additional data is not supported")
+	}
+
+	s, err := salsa20.NewUnauthenticatedCipher(c.key[:], nonce, 20)
+	if err != nil {
+		panic("salsa20poly1305: " + err.Error())
+	}
+
+	var polyKey [32]byte
+	s.XORKeyStream(polyKey[:], polyKey[:])
+
+	ret, out := sliceForAppend(dst, len(plaintext)+Overhead)
+	ciphertext, tag := out[:len(plaintext)], out[len(plaintext):]
+	s.XORKeyStream(ciphertext, plaintext)
+
+	var sum [16]byte
+	poly1305.Sum(&sum, ciphertext, &polyKey)
+	copy(tag, sum[:])
+
+	return ret
+}
+
+func (c *xsalsa20poly1305) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != NonceSize {
+		panic("salsa20poly1305: bad nonce length passed to Open")
+	}
+	if len(additionalData) != 0 {
+		panic("salsa20poly1305: additional data is not supported")
+	}
+	if len(ciphertext) < Overhead {
+		return nil, errOpen
+	}
+
+	s, err := salsa20.NewUnauthenticatedCipher(c.key[:], nonce, 20)
+	if err != nil {
+		return nil, err
+	}
+
+	var polyKey [32]byte
+	s.XORKeyStream(polyKey[:], polyKey[:])
+
+	msgLen := len(ciphertext) - Overhead
+	ciphertext, tag := ciphertext[:msgLen], ciphertext[msgLen:]
+
+	var sum [16]byte
+	poly1305.Sum(&sum, ciphertext, &polyKey)
+	if subtle.ConstantTimeCompare(sum[:], tag) != 1 {
+		return nil, errOpen
+	}
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	s.XORKeyStream(out, ciphertext)
+	return ret, nil
+}
+
+// sliceForAppend extends the in slice by n bytes. head is the full extended
+// slice, while tail is the appended part. If in has enough capacity, no
+// allocation occurs.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}